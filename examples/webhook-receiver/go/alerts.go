@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Event is the shape of a decoded delivery that alert rules match against and
+// providers are sent. Raw preserves the full body so providers that forward
+// the payload verbatim (e.g. a generic webhook) don't need to re-marshal a
+// reduced view of it.
+type Event struct {
+	EventID          string            `json:"event_id,omitempty"`
+	EventType        string            `json:"event_type"`
+	Organization     string            `json:"organization,omitempty"`
+	RepositoryName   string            `json:"repository_name,omitempty"`
+	CustomProperties map[string]string `json:"custom_properties,omitempty"`
+	Raw              json.RawMessage   `json:"-"`
+}
+
+// Alert is one declarative routing rule: deliveries matching EventTypes,
+// Organization and CustomProperties are sent to Provider. Inspired by flux's
+// notification-controller Alert CRD.
+type Alert struct {
+	Name             string            `toml:"name" yaml:"name"`
+	EventTypes       []string          `toml:"event_types" yaml:"event_types"`
+	Organization     string            `toml:"organization" yaml:"organization"`
+	CustomProperties map[string]string `toml:"custom_properties" yaml:"custom_properties"`
+	Provider         ProviderConfig    `toml:"provider" yaml:"provider"`
+}
+
+// ProviderConfig describes where and how an Alert's matches are delivered.
+type ProviderConfig struct {
+	// Type selects the provider implementation: slack, teams, webhook, http,
+	// stdout, or service-catalog.
+	Type string `toml:"type" yaml:"type"`
+
+	// URL is the destination endpoint. Unused by the stdout provider.
+	URL string `toml:"url" yaml:"url"`
+
+	// Retries is how many additional attempts are made after a failed send.
+	// Defaults to 3.
+	Retries int `toml:"retries" yaml:"retries"`
+
+	// Backoff is the delay before the first retry, doubling after each
+	// subsequent attempt. Defaults to 1 second.
+	Backoff time.Duration `toml:"backoff" yaml:"backoff"`
+}
+
+// AlertConfig is the top-level shape of an alert-routing config file.
+type AlertConfig struct {
+	Alerts []Alert `toml:"alert" yaml:"alerts"`
+}
+
+// LoadAlertConfigTOML reads and parses a TOML alert-routing config from path.
+func LoadAlertConfigTOML(path string) (*AlertConfig, error) {
+	var cfg AlertConfig
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return nil, fmt.Errorf("webhook: parse TOML alert config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// LoadAlertConfigYAML reads and parses a YAML alert-routing config from path.
+func LoadAlertConfigYAML(filePath string) (*AlertConfig, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: read YAML alert config: %w", err)
+	}
+
+	var cfg AlertConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("webhook: parse YAML alert config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// compiledAlert is an Alert with its provider constructed once at router
+// build time, rather than on every delivery.
+type compiledAlert struct {
+	alert    Alert
+	provider Provider
+}
+
+// AlertRouter matches decoded events against a set of Alert rules and fans
+// each match out to its configured Provider.
+type AlertRouter struct {
+	alerts  []compiledAlert
+	metrics *Metrics
+}
+
+// NewAlertRouter builds an AlertRouter from cfg, constructing (and validating)
+// every alert's provider up front so a misconfigured provider fails at
+// startup rather than on the first matching delivery. metrics may be nil, in
+// which case provider failures are logged but not counted.
+func NewAlertRouter(cfg *AlertConfig, metrics *Metrics) (*AlertRouter, error) {
+	router := &AlertRouter{alerts: make([]compiledAlert, 0, len(cfg.Alerts)), metrics: metrics}
+
+	for _, alert := range cfg.Alerts {
+		provider, err := newProvider(alert.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: alert %q: %w", alert.Name, err)
+		}
+		router.alerts = append(router.alerts, compiledAlert{alert: alert, provider: provider})
+	}
+
+	return router, nil
+}
+
+// Route sends event to every provider whose alert rule matches it, fanning
+// out the matching providers concurrently. Each provider failure is logged
+// (and counted, if metrics were configured) rather than stopping delivery to
+// the others. Route returns a combined error if any provider exhausted its
+// retries, so a caller like the outbox worker can decide whether to retry
+// the whole event later.
+func (r *AlertRouter) Route(ctx context.Context, event Event) error {
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+
+	for _, ca := range r.alerts {
+		if !ca.alert.matches(event) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(ca compiledAlert) {
+			defer wg.Done()
+			if err := sendWithRetry(ctx, ca.provider, event, ca.alert.Provider); err != nil {
+				slog.Error("Alert provider failed", "alert", ca.alert.Name, "provider", ca.alert.Provider.Type, "error", err)
+				if r.metrics != nil {
+					r.metrics.IncDispatchFailed(ca.alert.Provider.Type)
+				}
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("alert %q via %s: %w", ca.alert.Name, ca.alert.Provider.Type, err))
+				mu.Unlock()
+			}
+		}(ca)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// matches reports whether event satisfies every condition on a.
+func (a Alert) matches(event Event) bool {
+	if len(a.EventTypes) > 0 {
+		found := false
+		for _, et := range a.EventTypes {
+			if et == event.EventType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if a.Organization != "" {
+		ok, err := path.Match(a.Organization, event.Organization)
+		if err != nil || !ok {
+			return false
+		}
+	}
+
+	for key, want := range a.CustomProperties {
+		if event.CustomProperties[key] != want {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sendWithRetry calls provider.Send, retrying with exponential backoff on
+// failure per cfg.Retries and cfg.Backoff.
+func sendWithRetry(ctx context.Context, provider Provider, event Event, cfg ProviderConfig) error {
+	retries := cfg.Retries
+	if retries <= 0 {
+		retries = defaultProviderRetries
+	}
+	backoff := cfg.Backoff
+	if backoff <= 0 {
+		backoff = defaultProviderBackoff
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			backoff *= 2
+		}
+
+		if lastErr = provider.Send(ctx, event); lastErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", retries+1, lastErr)
+}
+
+// eventFromEnvelope builds an Event for alert routing from a delivery's raw
+// body and its already-parsed envelope fields.
+func eventFromEnvelope(env alertEnvelope, rawBody []byte) Event {
+	return Event{
+		EventID:          env.EventID,
+		EventType:        env.EventType,
+		Organization:     env.Organization,
+		RepositoryName:   env.RepositoryName,
+		CustomProperties: env.CustomProperties,
+		Raw:              json.RawMessage(rawBody),
+	}
+}
+
+// alertEnvelope reads the fields common enough across event payloads to
+// drive alert matching and outbox deduplication, without committing to one
+// event type's full schema.
+type alertEnvelope struct {
+	EventID          string            `json:"event_id"`
+	EventType        string            `json:"event_type"`
+	Organization     string            `json:"organization"`
+	RepositoryName   string            `json:"repository_name"`
+	CustomProperties map[string]string `json:"custom_properties,omitempty"`
+}
@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestAlert_Matches_EventTypes(t *testing.T) {
+	a := Alert{EventTypes: []string{"repository.created", "repository.deleted"}}
+
+	if !a.matches(Event{EventType: "repository.created"}) {
+		t.Fatal("expected a listed event type to match")
+	}
+	if a.matches(Event{EventType: "repository.updated"}) {
+		t.Fatal("expected an unlisted event type not to match")
+	}
+}
+
+func TestAlert_Matches_NoEventTypesMeansAny(t *testing.T) {
+	a := Alert{}
+	if !a.matches(Event{EventType: "anything"}) {
+		t.Fatal("expected an empty EventTypes list to match any event type")
+	}
+}
+
+func TestAlert_Matches_OrganizationGlob(t *testing.T) {
+	a := Alert{Organization: "acme-*"}
+
+	if !a.matches(Event{Organization: "acme-corp"}) {
+		t.Fatal("expected the organization glob to match")
+	}
+	if a.matches(Event{Organization: "other-corp"}) {
+		t.Fatal("expected the organization glob not to match")
+	}
+}
+
+func TestAlert_Matches_CustomProperties(t *testing.T) {
+	a := Alert{CustomProperties: map[string]string{"team": "platform", "tier": "1"}}
+
+	if !a.matches(Event{CustomProperties: map[string]string{"team": "platform", "tier": "1", "extra": "ignored"}}) {
+		t.Fatal("expected all required custom properties to match, extras ignored")
+	}
+	if a.matches(Event{CustomProperties: map[string]string{"team": "platform"}}) {
+		t.Fatal("expected a missing required custom property not to match")
+	}
+	if a.matches(Event{CustomProperties: map[string]string{"team": "platform", "tier": "2"}}) {
+		t.Fatal("expected a mismatched custom property value not to match")
+	}
+}
+
+func TestAlert_Matches_AllConditionsCombine(t *testing.T) {
+	a := Alert{
+		EventTypes:       []string{"repository.created"},
+		Organization:     "acme-*",
+		CustomProperties: map[string]string{"tier": "1"},
+	}
+	event := Event{
+		EventType:        "repository.created",
+		Organization:     "acme-corp",
+		CustomProperties: map[string]string{"tier": "1"},
+	}
+	if !a.matches(event) {
+		t.Fatal("expected an event satisfying every condition to match")
+	}
+
+	event.Organization = "other-corp"
+	if a.matches(event) {
+		t.Fatal("expected a failing organization condition to veto an otherwise-matching event")
+	}
+}
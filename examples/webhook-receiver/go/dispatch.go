@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// EventHandler is the type-erased form every registered handler is stored as,
+// so Dispatcher can keep them in a single map keyed by event type.
+type EventHandler func(ctx context.Context, rawPayload []byte) error
+
+// TypedHandler processes one event type's payload, already deserialised into
+// its concrete Go type.
+type TypedHandler[T any] func(ctx context.Context, payload T) error
+
+// Dispatcher routes a webhook delivery to the handler registered for its
+// event type, following the same eventTypeMapping pattern go-github uses to
+// turn a string event type into a concrete payload type. Unknown event types
+// are routed to OnUnknown instead of being silently dropped.
+type Dispatcher struct {
+	handlers  map[string]EventHandler
+	onUnknown func(ctx context.Context, eventType string, rawPayload []byte)
+}
+
+// NewDispatcher returns an empty Dispatcher. Use RegisterHandler to populate it.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: make(map[string]EventHandler)}
+}
+
+// RegisterHandler wires handler up to run whenever a delivery arrives with
+// the given eventType, unmarshalling the raw payload into T first. It is a
+// package-level function rather than a method because Go does not allow
+// generic methods on non-generic types.
+func RegisterHandler[T any](d *Dispatcher, eventType string, handler TypedHandler[T]) {
+	d.handlers[eventType] = func(ctx context.Context, rawPayload []byte) error {
+		var payload T
+		if err := json.Unmarshal(rawPayload, &payload); err != nil {
+			return fmt.Errorf("webhook: deserialise %s payload: %w", eventType, err)
+		}
+		return handler(ctx, payload)
+	}
+}
+
+// OnUnknown registers the catch-all hook invoked for event types with no
+// registered handler, so downstream users can forward them to a dead-letter
+// queue instead of losing them to a log line.
+func (d *Dispatcher) OnUnknown(fn func(ctx context.Context, eventType string, rawPayload []byte)) {
+	d.onUnknown = fn
+}
+
+// Dispatch routes rawPayload to the handler registered for eventType. If no
+// handler is registered, it calls the OnUnknown hook (if any) and returns nil.
+func (d *Dispatcher) Dispatch(ctx context.Context, eventType string, rawPayload []byte) error {
+	handler, ok := d.handlers[eventType]
+	if !ok {
+		if d.onUnknown != nil {
+			d.onUnknown(ctx, eventType, rawPayload)
+		}
+		return nil
+	}
+	return handler(ctx, rawPayload)
+}
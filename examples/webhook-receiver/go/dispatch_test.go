@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type testPayload struct {
+	Name string `json:"name"`
+}
+
+func TestDispatcher_RoutesToRegisteredHandler(t *testing.T) {
+	d := NewDispatcher()
+
+	var got testPayload
+	RegisterHandler(d, "test.event", func(_ context.Context, payload testPayload) error {
+		got = payload
+		return nil
+	})
+
+	err := d.Dispatch(context.Background(), "test.event", []byte(`{"name":"widget"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != "widget" {
+		t.Fatalf("expected handler to receive deserialised payload, got %+v", got)
+	}
+}
+
+func TestDispatcher_PropagatesHandlerError(t *testing.T) {
+	d := NewDispatcher()
+	wantErr := errors.New("boom")
+
+	RegisterHandler(d, "test.event", func(_ context.Context, _ testPayload) error {
+		return wantErr
+	})
+
+	err := d.Dispatch(context.Background(), "test.event", []byte(`{}`))
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("expected handler error to propagate, got %v", err)
+	}
+}
+
+func TestDispatcher_UnregisteredEventTypeCallsOnUnknown(t *testing.T) {
+	d := NewDispatcher()
+
+	var gotEventType string
+	var gotPayload []byte
+	d.OnUnknown(func(_ context.Context, eventType string, rawPayload []byte) {
+		gotEventType = eventType
+		gotPayload = rawPayload
+	})
+
+	err := d.Dispatch(context.Background(), "unknown.event", []byte(`{"x":1}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotEventType != "unknown.event" {
+		t.Fatalf("expected OnUnknown to be called with the event type, got %q", gotEventType)
+	}
+	if string(gotPayload) != `{"x":1}` {
+		t.Fatalf("expected OnUnknown to receive the raw payload, got %q", gotPayload)
+	}
+}
+
+func TestDispatcher_UnregisteredEventTypeWithNoOnUnknownIsANoop(t *testing.T) {
+	d := NewDispatcher()
+	if err := d.Dispatch(context.Background(), "unknown.event", []byte(`{}`)); err != nil {
+		t.Fatalf("expected no error when no handler and no OnUnknown are registered, got %v", err)
+	}
+}
+
+func TestDispatcher_InvalidPayloadReturnsError(t *testing.T) {
+	d := NewDispatcher()
+	RegisterHandler(d, "test.event", func(_ context.Context, _ testPayload) error {
+		return nil
+	})
+
+	if err := d.Dispatch(context.Background(), "test.event", []byte(`not json`)); err == nil {
+		t.Fatal("expected an error deserialising an invalid payload")
+	}
+}
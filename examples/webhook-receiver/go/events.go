@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+// ---------------------------------------------------------------------------
+// Event payload types
+// ---------------------------------------------------------------------------
+
+// RepositoryCreatedPayload contains all fields sent in a repository.created event.
+// Optional fields use pointer types so absent fields deserialise as nil.
+type RepositoryCreatedPayload struct {
+	EventType        string            `json:"event_type"`
+	EventID          string            `json:"event_id"`
+	Timestamp        string            `json:"timestamp"`
+	Organization     string            `json:"organization"`
+	RepositoryName   string            `json:"repository_name"`
+	RepositoryURL    string            `json:"repository_url"`
+	RepositoryID     string            `json:"repository_id"`
+	CreatedBy        string            `json:"created_by"`
+	RepositoryType   *string           `json:"repository_type,omitempty"`
+	TemplateName     *string           `json:"template_name,omitempty"`
+	ContentStrategy  string            `json:"content_strategy"`
+	Visibility       string            `json:"visibility"`
+	Team             *string           `json:"team,omitempty"`
+	Description      *string           `json:"description,omitempty"`
+	CustomProperties map[string]string `json:"custom_properties,omitempty"`
+}
+
+// RepositoryArchivedPayload contains all fields sent in a repository.archived event.
+type RepositoryArchivedPayload struct {
+	EventType      string  `json:"event_type"`
+	EventID        string  `json:"event_id"`
+	Timestamp      string  `json:"timestamp"`
+	Organization   string  `json:"organization"`
+	RepositoryName string  `json:"repository_name"`
+	RepositoryID   string  `json:"repository_id"`
+	ArchivedBy     string  `json:"archived_by"`
+	Reason         *string `json:"reason,omitempty"`
+}
+
+// RepositoryDeletedPayload contains all fields sent in a repository.deleted event.
+type RepositoryDeletedPayload struct {
+	EventType      string `json:"event_type"`
+	EventID        string `json:"event_id"`
+	Timestamp      string `json:"timestamp"`
+	Organization   string `json:"organization"`
+	RepositoryName string `json:"repository_name"`
+	RepositoryID   string `json:"repository_id"`
+	DeletedBy      string `json:"deleted_by"`
+}
+
+// RepositoryUpdatedPayload contains all fields sent in a repository.updated event.
+type RepositoryUpdatedPayload struct {
+	EventType      string   `json:"event_type"`
+	EventID        string   `json:"event_id"`
+	Timestamp      string   `json:"timestamp"`
+	Organization   string   `json:"organization"`
+	RepositoryName string   `json:"repository_name"`
+	RepositoryID   string   `json:"repository_id"`
+	UpdatedBy      string   `json:"updated_by"`
+	ChangedFields  []string `json:"changed_fields,omitempty"`
+}
+
+// TemplateAppliedPayload contains all fields sent in a template.applied event.
+type TemplateAppliedPayload struct {
+	EventType      string `json:"event_type"`
+	EventID        string `json:"event_id"`
+	Timestamp      string `json:"timestamp"`
+	Organization   string `json:"organization"`
+	RepositoryName string `json:"repository_name"`
+	TemplateName   string `json:"template_name"`
+	AppliedBy      string `json:"applied_by"`
+}
+
+// SettingsDriftDetectedPayload contains all fields sent in a
+// settings.drift_detected event, raised when a repository's settings no
+// longer match what its template declares.
+type SettingsDriftDetectedPayload struct {
+	EventType       string   `json:"event_type"`
+	EventID         string   `json:"event_id"`
+	Timestamp       string   `json:"timestamp"`
+	Organization    string   `json:"organization"`
+	RepositoryName  string   `json:"repository_name"`
+	DriftedSettings []string `json:"drifted_settings,omitempty"`
+	DetectedBy      string   `json:"detected_by"`
+}
+
+// ---------------------------------------------------------------------------
+// Event handlers
+// ---------------------------------------------------------------------------
+
+// newDispatcher builds a Dispatcher with the default handler registered for
+// every event type RepoRoller currently sends. Callers that want to react to
+// events themselves should register additional handlers after construction,
+// or override via RegisterHandler to replace a default.
+func newDispatcher() *Dispatcher {
+	d := NewDispatcher()
+
+	RegisterHandler(d, "repository.created", func(_ context.Context, payload RepositoryCreatedPayload) error {
+		handleRepositoryCreated(payload)
+		return nil
+	})
+	RegisterHandler(d, "repository.archived", func(_ context.Context, payload RepositoryArchivedPayload) error {
+		handleRepositoryArchived(payload)
+		return nil
+	})
+	RegisterHandler(d, "repository.deleted", func(_ context.Context, payload RepositoryDeletedPayload) error {
+		handleRepositoryDeleted(payload)
+		return nil
+	})
+	RegisterHandler(d, "repository.updated", func(_ context.Context, payload RepositoryUpdatedPayload) error {
+		handleRepositoryUpdated(payload)
+		return nil
+	})
+	RegisterHandler(d, "template.applied", func(_ context.Context, payload TemplateAppliedPayload) error {
+		handleTemplateApplied(payload)
+		return nil
+	})
+	RegisterHandler(d, "settings.drift_detected", func(_ context.Context, payload SettingsDriftDetectedPayload) error {
+		handleSettingsDriftDetected(payload)
+		return nil
+	})
+
+	d.OnUnknown(func(_ context.Context, eventType string, _ []byte) {
+		slog.Info("Ignoring unknown event type", "event_type", eventType)
+	})
+
+	return d
+}
+
+func handleRepositoryCreated(payload RepositoryCreatedPayload) {
+	templateName := "(none)"
+	if payload.TemplateName != nil {
+		templateName = *payload.TemplateName
+	}
+	team := "(none)"
+	if payload.Team != nil {
+		team = *payload.Team
+	}
+
+	slog.Info("Repository created",
+		"event_id", payload.EventID,
+		"org", payload.Organization,
+		"name", payload.RepositoryName,
+		"url", payload.RepositoryURL,
+		"created_by", payload.CreatedBy,
+		"visibility", payload.Visibility,
+		"template", templateName,
+		"team", team,
+		"strategy", payload.ContentStrategy,
+	)
+
+	// Add your integration logic here:
+	//   - Post a Slack / Teams notification
+	//   - Register the repo in a service catalog
+	//   - Trigger a CI provisioning pipeline
+	//   - Update an asset inventory database
+}
+
+func handleRepositoryArchived(payload RepositoryArchivedPayload) {
+	reason := "(none)"
+	if payload.Reason != nil {
+		reason = *payload.Reason
+	}
+
+	slog.Info("Repository archived",
+		"event_id", payload.EventID,
+		"org", payload.Organization,
+		"name", payload.RepositoryName,
+		"archived_by", payload.ArchivedBy,
+		"reason", reason,
+	)
+}
+
+func handleRepositoryDeleted(payload RepositoryDeletedPayload) {
+	slog.Info("Repository deleted",
+		"event_id", payload.EventID,
+		"org", payload.Organization,
+		"name", payload.RepositoryName,
+		"deleted_by", payload.DeletedBy,
+	)
+}
+
+func handleRepositoryUpdated(payload RepositoryUpdatedPayload) {
+	slog.Info("Repository updated",
+		"event_id", payload.EventID,
+		"org", payload.Organization,
+		"name", payload.RepositoryName,
+		"updated_by", payload.UpdatedBy,
+		"changed_fields", payload.ChangedFields,
+	)
+}
+
+func handleTemplateApplied(payload TemplateAppliedPayload) {
+	slog.Info("Template applied",
+		"event_id", payload.EventID,
+		"org", payload.Organization,
+		"name", payload.RepositoryName,
+		"template", payload.TemplateName,
+		"applied_by", payload.AppliedBy,
+	)
+}
+
+func handleSettingsDriftDetected(payload SettingsDriftDetectedPayload) {
+	slog.Warn("Settings drift detected",
+		"event_id", payload.EventID,
+		"org", payload.Organization,
+		"name", payload.RepositoryName,
+		"drifted_settings", payload.DriftedSettings,
+		"detected_by", payload.DetectedBy,
+	)
+}
@@ -0,0 +1,33 @@
+package main
+
+import "context"
+
+// handshakeEventType is the event type RepoRoller sends when it needs a
+// receiver to prove it controls an endpoint: on first registration, and again
+// whenever the shared secret is rotated.
+const handshakeEventType = "webhook_callback_verification"
+
+// HandshakePayload is the body RepoRoller sends during a subscription-
+// verification handshake.
+type HandshakePayload struct {
+	EventType string `json:"event_type"`
+	Challenge string `json:"challenge"`
+}
+
+// HandshakeHandler produces the text a receiver must echo back to prove it
+// controls the registered endpoint, mirroring Twitch EventSub's callback
+// verification. Most callers can use the default handler, which simply
+// echoes the challenge unchanged; the interface exists so a receiver can
+// reject a handshake it didn't expect (e.g. one arriving outside a secret
+// rotation window).
+type HandshakeHandler interface {
+	HandleHandshake(ctx context.Context, challenge string) (string, error)
+}
+
+// echoHandshakeHandler is the default HandshakeHandler. It echoes the
+// challenge verbatim, which is all RepoRoller requires.
+type echoHandshakeHandler struct{}
+
+func (echoHandshakeHandler) HandleHandshake(_ context.Context, challenge string) (string, error) {
+	return challenge, nil
+}
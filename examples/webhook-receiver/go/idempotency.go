@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// OutboxEntry is one event waiting for (or retrying) delivery to the alert
+// providers.
+type OutboxEntry struct {
+	EventID     string
+	Event       Event
+	Attempts    int
+	NextAttempt time.Time
+}
+
+// IdempotencyStore records which events a Receiver has already accepted and
+// durably queues them for the outbox worker to drain, so a retried delivery
+// from RepoRoller is detected and short-circuited rather than re-run through
+// the handlers and alert providers a second time.
+//
+// Enqueue is the single write a delivery's acknowledgement depends on: once
+// it returns, the event is safe on disk (or in Redis) even if the process
+// crashes before the outbox worker gets to it.
+type IdempotencyStore interface {
+	// Enqueue durably records event under eventID if it hasn't been seen
+	// before. accepted is false, with no error, when eventID is a duplicate.
+	Enqueue(ctx context.Context, eventID string, event Event) (accepted bool, err error)
+
+	// Claim returns up to max entries whose NextAttempt has passed, for the
+	// outbox worker to attempt delivery on.
+	Claim(ctx context.Context, max int) ([]OutboxEntry, error)
+
+	// Complete removes eventID from the outbox after successful delivery to
+	// every matching provider.
+	Complete(ctx context.Context, eventID string) error
+
+	// Retry reschedules eventID for another delivery attempt at nextAttempt,
+	// incrementing its attempt count.
+	Retry(ctx context.Context, eventID string, nextAttempt time.Time) error
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore backed by an in-process map.
+// It satisfies the interface for local development and tests, but its state
+// does not survive a restart.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*OutboxEntry
+}
+
+// NewMemoryIdempotencyStore returns an empty MemoryIdempotencyStore.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: make(map[string]*OutboxEntry)}
+}
+
+func (s *MemoryIdempotencyStore) Enqueue(_ context.Context, eventID string, event Event) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[eventID]; exists {
+		return false, nil
+	}
+	s.entries[eventID] = &OutboxEntry{EventID: eventID, Event: event}
+	return true, nil
+}
+
+func (s *MemoryIdempotencyStore) Claim(_ context.Context, max int) ([]OutboxEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	claimed := make([]OutboxEntry, 0, max)
+	for _, entry := range s.entries {
+		if len(claimed) >= max {
+			break
+		}
+		if entry.NextAttempt.After(now) {
+			continue
+		}
+		claimed = append(claimed, *entry)
+	}
+	return claimed, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, eventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, eventID)
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Retry(_ context.Context, eventID string, nextAttempt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[eventID]
+	if !ok {
+		return nil
+	}
+	entry.Attempts++
+	entry.NextAttempt = nextAttempt
+	return nil
+}
+
+// wireEvent is the durable, JSON-serialisable form of an Event. Event.Raw is
+// tagged json:"-" so alert matching doesn't double-encode it; the outbox
+// stores need the raw body preserved across a restart, so they marshal
+// through this type instead.
+type wireEvent struct {
+	EventType        string            `json:"event_type"`
+	Organization     string            `json:"organization,omitempty"`
+	RepositoryName   string            `json:"repository_name,omitempty"`
+	CustomProperties map[string]string `json:"custom_properties,omitempty"`
+	Raw              json.RawMessage   `json:"raw"`
+}
+
+func marshalEvent(event Event) ([]byte, error) {
+	data, err := json.Marshal(wireEvent{
+		EventType:        event.EventType,
+		Organization:     event.Organization,
+		RepositoryName:   event.RepositoryName,
+		CustomProperties: event.CustomProperties,
+		Raw:              event.Raw,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("webhook: marshal outbox event: %w", err)
+	}
+	return data, nil
+}
+
+func unmarshalEvent(data []byte) (Event, error) {
+	var wire wireEvent
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return Event{}, fmt.Errorf("webhook: unmarshal outbox event: %w", err)
+	}
+	return Event{
+		EventType:        wire.EventType,
+		Organization:     wire.Organization,
+		RepositoryName:   wire.RepositoryName,
+		CustomProperties: wire.CustomProperties,
+		Raw:              wire.Raw,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func idempotencyStores(t *testing.T) map[string]IdempotencyStore {
+	t.Helper()
+
+	sqliteStore, err := NewSQLiteIdempotencyStore(filepath.Join(t.TempDir(), "outbox.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { sqliteStore.Close() })
+
+	return map[string]IdempotencyStore{
+		"memory": NewMemoryIdempotencyStore(),
+		"sqlite": sqliteStore,
+	}
+}
+
+func TestIdempotencyStore_EnqueueRejectsDuplicate(t *testing.T) {
+	ctx := context.Background()
+	event := Event{EventType: "repository.created", Organization: "acme"}
+
+	for name, store := range idempotencyStores(t) {
+		t.Run(name, func(t *testing.T) {
+			accepted, err := store.Enqueue(ctx, "evt-1", event)
+			if err != nil {
+				t.Fatalf("enqueue: %v", err)
+			}
+			if !accepted {
+				t.Fatal("expected the first enqueue of an event ID to be accepted")
+			}
+
+			accepted, err = store.Enqueue(ctx, "evt-1", event)
+			if err != nil {
+				t.Fatalf("enqueue duplicate: %v", err)
+			}
+			if accepted {
+				t.Fatal("expected a duplicate event ID to be rejected")
+			}
+		})
+	}
+}
+
+func TestIdempotencyStore_ClaimCompleteRetry(t *testing.T) {
+	ctx := context.Background()
+	event := Event{EventType: "repository.created", Organization: "acme"}
+
+	for name, store := range idempotencyStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Enqueue(ctx, "evt-1", event); err != nil {
+				t.Fatalf("enqueue: %v", err)
+			}
+
+			claimed, err := store.Claim(ctx, 10)
+			if err != nil {
+				t.Fatalf("claim: %v", err)
+			}
+			if len(claimed) != 1 || claimed[0].EventID != "evt-1" {
+				t.Fatalf("expected to claim the enqueued entry, got %+v", claimed)
+			}
+			if claimed[0].Event.EventType != event.EventType {
+				t.Fatalf("expected the claimed entry to round-trip the event, got %+v", claimed[0].Event)
+			}
+
+			// Retry should push the entry's next attempt into the future, so it
+			// is not claimable again until that time passes.
+			if err := store.Retry(ctx, "evt-1", time.Now().Add(time.Hour)); err != nil {
+				t.Fatalf("retry: %v", err)
+			}
+			claimed, err = store.Claim(ctx, 10)
+			if err != nil {
+				t.Fatalf("claim after retry: %v", err)
+			}
+			if len(claimed) != 0 {
+				t.Fatalf("expected no claimable entries before the retry's next attempt, got %+v", claimed)
+			}
+
+			if err := store.Complete(ctx, "evt-1"); err != nil {
+				t.Fatalf("complete: %v", err)
+			}
+
+			// A completed entry must no longer be claimable even once its
+			// (now-irrelevant) next-attempt time would have passed.
+			claimed, err = store.Claim(ctx, 10)
+			if err != nil {
+				t.Fatalf("claim after complete: %v", err)
+			}
+			if len(claimed) != 0 {
+				t.Fatalf("expected no claimable entries after Complete, got %+v", claimed)
+			}
+		})
+	}
+}
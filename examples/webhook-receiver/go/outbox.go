@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// outboxPollInterval is how often the worker checks for claimable entries.
+const outboxPollInterval = 5 * time.Second
+
+// outboxBatchSize bounds how many entries are claimed per poll.
+const outboxBatchSize = 20
+
+// outboxMaxAttempts is how many delivery attempts an entry gets before the
+// worker gives up on it and drops it from the outbox. Each attempt already
+// fans out to every matching provider with its own retries, so this bounds
+// retries of the whole event against a provider that is down for longer than
+// its own backoff window covers.
+const outboxMaxAttempts = 5
+
+// outboxBaseBackoff is the delay before an entry's second attempt, doubling
+// (and capped by outboxMaxBackoff) after each subsequent failure.
+const outboxBaseBackoff = 30 * time.Second
+
+// outboxMaxBackoff caps the delay between attempts.
+const outboxMaxBackoff = 30 * time.Minute
+
+// OutboxWorker drains an IdempotencyStore's outbox, routing each claimed
+// event through an AlertRouter and rescheduling it with exponential backoff
+// on failure. This is what turns Receiver.webhookHandler's durable
+// Enqueue-then-204 into an at-least-once delivery rather than a fire-and-forget
+// one: an event survives a process restart until every matching provider has
+// actually accepted it.
+type OutboxWorker struct {
+	store  IdempotencyStore
+	alerts *AlertRouter
+}
+
+// NewOutboxWorker builds an OutboxWorker draining store into alerts.
+func NewOutboxWorker(store IdempotencyStore, alerts *AlertRouter) *OutboxWorker {
+	return &OutboxWorker{store: store, alerts: alerts}
+}
+
+// Run polls the outbox until ctx is cancelled. It is meant to be started in
+// its own goroutine.
+func (w *OutboxWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.drain(ctx)
+		}
+	}
+}
+
+func (w *OutboxWorker) drain(ctx context.Context) {
+	entries, err := w.store.Claim(ctx, outboxBatchSize)
+	if err != nil {
+		slog.Error("Failed to claim outbox entries", "error", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if err := w.alerts.Route(ctx, entry.Event); err != nil {
+			w.handleFailure(ctx, entry, err)
+			continue
+		}
+
+		if err := w.store.Complete(ctx, entry.EventID); err != nil {
+			slog.Error("Failed to complete outbox entry", "event_id", entry.EventID, "error", err)
+		}
+	}
+}
+
+func (w *OutboxWorker) handleFailure(ctx context.Context, entry OutboxEntry, err error) {
+	if entry.Attempts+1 >= outboxMaxAttempts {
+		slog.Error("Permanently failed to deliver event, dropping from outbox",
+			"event_id", entry.EventID, "attempts", entry.Attempts+1, "error", err)
+		if completeErr := w.store.Complete(ctx, entry.EventID); completeErr != nil {
+			slog.Error("Failed to drop permanently failed outbox entry", "event_id", entry.EventID, "error", completeErr)
+		}
+		return
+	}
+
+	backoff := outboxBaseBackoff << entry.Attempts
+	if backoff > outboxMaxBackoff || backoff <= 0 {
+		backoff = outboxMaxBackoff
+	}
+
+	if retryErr := w.store.Retry(ctx, entry.EventID, time.Now().Add(backoff)); retryErr != nil {
+		slog.Error("Failed to reschedule outbox entry", "event_id", entry.EventID, "error", retryErr)
+	}
+}
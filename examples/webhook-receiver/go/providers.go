@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// defaultProviderRetries and defaultProviderBackoff apply when a
+// ProviderConfig leaves Retries / Backoff unset.
+const (
+	defaultProviderRetries = 3
+	defaultProviderBackoff = 1 * time.Second
+)
+
+// defaultProviderTimeout bounds how long a single send attempt may take.
+const defaultProviderTimeout = 10 * time.Second
+
+// Provider delivers a matched Event to one destination — a chat channel, a
+// generic HTTP endpoint, or wherever else an alert is routed. Users can plug
+// in their own by implementing this interface and constructing an AlertRouter
+// by hand instead of via newProvider.
+type Provider interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// newProvider builds the Provider named by cfg.Type.
+func newProvider(cfg ProviderConfig) (Provider, error) {
+	client := &http.Client{Timeout: defaultProviderTimeout}
+
+	switch cfg.Type {
+	case "stdout":
+		return stdoutProvider{}, nil
+	case "webhook", "http":
+		return &httpProvider{url: cfg.URL, client: client, format: formatRawEvent}, nil
+	case "slack":
+		return &httpProvider{url: cfg.URL, client: client, format: formatChatMessage}, nil
+	case "teams":
+		return &httpProvider{url: cfg.URL, client: client, format: formatTeamsMessageCard}, nil
+	case "service-catalog":
+		return &httpProvider{url: cfg.URL, client: client, format: formatRawEvent}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", cfg.Type)
+	}
+}
+
+// stdoutProvider logs matched events instead of forwarding them anywhere.
+// Useful for trying out an alert config before pointing it at a real
+// endpoint.
+type stdoutProvider struct{}
+
+func (stdoutProvider) Send(_ context.Context, event Event) error {
+	slog.Info("alert",
+		"event_type", event.EventType,
+		"organization", event.Organization,
+		"repository", event.RepositoryName,
+	)
+	return nil
+}
+
+// httpProvider POSTs a formatted event body to a fixed URL. It backs the
+// webhook, http, slack, teams and service-catalog provider types — they only
+// differ in how the body is formatted.
+type httpProvider struct {
+	url    string
+	client *http.Client
+	format func(Event) ([]byte, error)
+}
+
+func (p *httpProvider) Send(ctx context.Context, event Event) error {
+	body, err := p.format(event)
+	if err != nil {
+		return fmt.Errorf("format event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("provider responded %s", resp.Status)
+	}
+	return nil
+}
+
+// formatRawEvent forwards the event's raw JSON body unchanged, for providers
+// that accept RepoRoller's native payload shape.
+func formatRawEvent(event Event) ([]byte, error) {
+	return event.Raw, nil
+}
+
+// formatChatMessage renders a short summary line as {"text": "..."}, the
+// message shape Slack incoming webhooks accept.
+func formatChatMessage(event Event) ([]byte, error) {
+	text := fmt.Sprintf("[%s] %s/%s", event.EventType, event.Organization, event.RepositoryName)
+	return json.Marshal(map[string]string{"text": text})
+}
+
+// teamsMessageCard is the Office 365 Connector MessageCard shape Microsoft
+// Teams incoming webhooks require — unlike Slack, a bare {"text": "..."} body
+// is rejected.
+type teamsMessageCard struct {
+	Type       string `json:"@type"`
+	Context    string `json:"@context"`
+	Summary    string `json:"summary"`
+	ThemeColor string `json:"themeColor,omitempty"`
+	Text       string `json:"text"`
+}
+
+// formatTeamsMessageCard renders a short summary line as a MessageCard, the
+// message shape Microsoft Teams incoming webhooks accept.
+func formatTeamsMessageCard(event Event) ([]byte, error) {
+	text := fmt.Sprintf("[%s] %s/%s", event.EventType, event.Organization, event.RepositoryName)
+	return json.Marshal(teamsMessageCard{
+		Type:    "MessageCard",
+		Context: "http://schema.org/extensions",
+		Summary: text,
+		Text:    text,
+	})
+}
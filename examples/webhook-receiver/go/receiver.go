@@ -1,220 +1,467 @@
-// RepoRoller Webhook Receiver — Go Example
-// ==========================================
-// Demonstrates how to receive and verify RepoRoller outbound webhook notifications.
-//
-// Requirements:
-//
-//	Go 1.21+  (only stdlib — no external dependencies)
-//
-// Usage:
-//
-//	WEBHOOK_SECRET="your-shared-secret-value" go run receiver.go
-//
-// The server listens on port 8080 and accepts POST /webhook requests.
-//
-// See docs/notifications.md for full webhook documentation.
-package main
-
-import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
-	"fmt"
-	"io"
-	"log/slog"
-	"net/http"
-	"os"
-	"strconv"
-	"strings"
-)
-
-// ---------------------------------------------------------------------------
-// Configuration
-// ---------------------------------------------------------------------------
-
-var webhookSecret []byte
-
-func init() {
-	secret := os.Getenv("WEBHOOK_SECRET")
-	if secret == "" {
-		slog.Error("WEBHOOK_SECRET environment variable is not set")
-		os.Exit(1)
-	}
-	webhookSecret = []byte(secret)
-}
-
-func port() string {
-	p := os.Getenv("PORT")
-	if p == "" {
-		return "8080"
-	}
-	if _, err := strconv.Atoi(p); err != nil {
-		slog.Error("PORT must be a number", "value", p)
-		os.Exit(1)
-	}
-	return p
-}
-
-// ---------------------------------------------------------------------------
-// Event payload types
-// ---------------------------------------------------------------------------
-
-// RepositoryCreatedPayload contains all fields sent in a repository.created event.
-// Optional fields use pointer types so absent fields deserialise as nil.
-type RepositoryCreatedPayload struct {
-	EventType        string            `json:"event_type"`
-	EventID          string            `json:"event_id"`
-	Timestamp        string            `json:"timestamp"`
-	Organization     string            `json:"organization"`
-	RepositoryName   string            `json:"repository_name"`
-	RepositoryURL    string            `json:"repository_url"`
-	RepositoryID     string            `json:"repository_id"`
-	CreatedBy        string            `json:"created_by"`
-	RepositoryType   *string           `json:"repository_type,omitempty"`
-	TemplateName     *string           `json:"template_name,omitempty"`
-	ContentStrategy  string            `json:"content_strategy"`
-	Visibility       string            `json:"visibility"`
-	Team             *string           `json:"team,omitempty"`
-	Description      *string           `json:"description,omitempty"`
-	CustomProperties map[string]string `json:"custom_properties,omitempty"`
-}
-
-// genericPayload is used only to read the event_type field before full deserialisation.
-type genericPayload struct {
-	EventType string `json:"event_type"`
-}
-
-// ---------------------------------------------------------------------------
-// Signature verification
-// ---------------------------------------------------------------------------
-
-// verifySignature returns true when signatureHeader matches the HMAC-SHA256 of
-// rawBody using the shared secret.
-//
-// Uses hmac.Equal (constant-time) to prevent timing attacks.
-func verifySignature(rawBody []byte, signatureHeader string) bool {
-	const prefix = "sha256="
-	if !strings.HasPrefix(signatureHeader, prefix) {
-		return false
-	}
-
-	receivedHex := signatureHeader[len(prefix):]
-	receivedBytes, err := hex.DecodeString(receivedHex)
-	if err != nil {
-		return false
-	}
-
-	mac := hmac.New(sha256.New, webhookSecret)
-	mac.Write(rawBody)
-	computed := mac.Sum(nil)
-
-	// Constant-time comparison — never use bytes.Equal here.
-	return hmac.Equal(computed, receivedBytes)
-}
-
-// ---------------------------------------------------------------------------
-// Event handlers
-// ---------------------------------------------------------------------------
-
-func handleRepositoryCreated(payload RepositoryCreatedPayload) {
-	templateName := "(none)"
-	if payload.TemplateName != nil {
-		templateName = *payload.TemplateName
-	}
-	team := "(none)"
-	if payload.Team != nil {
-		team = *payload.Team
-	}
-
-	slog.Info("Repository created",
-		"event_id", payload.EventID,
-		"org", payload.Organization,
-		"name", payload.RepositoryName,
-		"url", payload.RepositoryURL,
-		"created_by", payload.CreatedBy,
-		"visibility", payload.Visibility,
-		"template", templateName,
-		"team", team,
-		"strategy", payload.ContentStrategy,
-	)
-
-	// Add your integration logic here:
-	//   - Post a Slack / Teams notification
-	//   - Register the repo in a service catalog
-	//   - Trigger a CI provisioning pipeline
-	//   - Update an asset inventory database
-}
-
-// ---------------------------------------------------------------------------
-// HTTP handler
-// ---------------------------------------------------------------------------
-
-func webhookHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Read the full body BEFORE parsing — signature covers the raw bytes.
-	rawBody, err := io.ReadAll(io.LimitReader(r.Body, 1<<20)) // 1 MiB limit
-	if err != nil {
-		slog.Error("Failed to read request body", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
-	defer r.Body.Close()
-
-	// 1. Verify signature.
-	sigHeader := r.Header.Get("X-RepoRoller-Signature-256")
-	if !verifySignature(rawBody, sigHeader) {
-		slog.Warn("Rejected request with invalid signature", "remote_addr", r.RemoteAddr)
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	// 2. Determine event type.
-	var env genericPayload
-	if err := json.Unmarshal(rawBody, &env); err != nil {
-		slog.Error("Failed to parse JSON body", "error", err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
-		return
-	}
-
-	// 3. Dispatch on event type.
-	switch env.EventType {
-	case "repository.created":
-		var payload RepositoryCreatedPayload
-		if err := json.Unmarshal(rawBody, &payload); err != nil {
-			slog.Error("Failed to deserialise repository.created payload", "error", err)
-			http.Error(w, "Bad Request", http.StatusBadRequest)
-			return
-		}
-		handleRepositoryCreated(payload)
-
-	default:
-		slog.Info("Ignoring unknown event type", "event_type", env.EventType)
-	}
-
-	// Always acknowledge promptly — processing is fire-and-forget from sender's perspective.
-	w.WriteHeader(http.StatusNoContent)
-}
-
-// ---------------------------------------------------------------------------
-// Entry point
-// ---------------------------------------------------------------------------
-
-func main() {
-	addr := fmt.Sprintf("0.0.0.0:%s", port())
-
-	mux := http.NewServeMux()
-	mux.HandleFunc("/webhook", webhookHandler)
-
-	slog.Info("RepoRoller webhook receiver listening", "addr", addr)
-	// In production, terminate TLS at a reverse proxy / load balancer.
-	// Your notifications.toml endpoint URL must use https://.
-	if err := http.ListenAndServe(addr, mux); err != nil {
-		slog.Error("Server failed", "error", err)
-		os.Exit(1)
-	}
-}
+// RepoRoller Webhook Receiver — Go Example
+// ==========================================
+// Demonstrates how to receive and verify RepoRoller outbound webhook notifications.
+//
+// Requirements:
+//
+//	Go 1.21+
+//
+// Usage:
+//
+//	WEBHOOK_SECRET="your-shared-secret-value" go run .
+//
+// The server listens on port 8080 and accepts POST /webhook requests.
+//
+// See docs/notifications.md for full webhook documentation.
+package main
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Configuration
+// ---------------------------------------------------------------------------
+
+// defaultTimestampTolerance is how far a delivery's X-RepoRoller-Timestamp is
+// allowed to drift from wall clock, in either direction, before it is rejected
+// as a possible replay.
+const defaultTimestampTolerance = 5 * time.Minute
+
+// defaultDeliveryCacheSize caps how many delivery IDs are remembered for
+// deduplication purposes.
+const defaultDeliveryCacheSize = 10_000
+
+// defaultDeliveryCacheTTL is how long a delivery ID is remembered after it is
+// first seen.
+const defaultDeliveryCacheTTL = 10 * time.Minute
+
+// defaultMinimumAlgorithm is the weakest signature algorithm trusted when
+// ReceiverConfig.MinimumAlgorithm is left unset.
+const defaultMinimumAlgorithm = AlgorithmSHA256
+
+// ReceiverConfig controls signature verification and replay protection for a
+// Receiver. Zero-value fields fall back to sensible defaults in NewReceiver.
+type ReceiverConfig struct {
+	// Secret is the shared HMAC secret configured on the RepoRoller side.
+	Secret []byte
+
+	// TimestampTolerance bounds how far the X-RepoRoller-Timestamp header may
+	// drift from wall clock before a request is rejected. Defaults to 5 minutes.
+	TimestampTolerance time.Duration
+
+	// DeliveryCacheSize is the maximum number of delivery IDs remembered for
+	// deduplication. Defaults to 10,000.
+	DeliveryCacheSize int
+
+	// DeliveryCacheTTL is how long a delivery ID stays in the dedup cache.
+	// Defaults to 10 minutes.
+	DeliveryCacheTTL time.Duration
+
+	// MinimumAlgorithm is the weakest signature algorithm the receiver will
+	// accept. Deliveries signed only with a weaker algorithm are rejected even
+	// if their signature is otherwise valid. Defaults to AlgorithmSHA256; set
+	// to AlgorithmSHA512 to forbid sha1 and sha256 entirely.
+	MinimumAlgorithm Algorithm
+
+	// Handshake responds to subscription-verification challenges RepoRoller
+	// sends on endpoint registration and secret rotation. Defaults to echoing
+	// the challenge back unchanged, which is all RepoRoller requires.
+	Handshake HandshakeHandler
+
+	// Dispatcher routes a delivery's event type to its handler. Defaults to
+	// newDispatcher(), which logs every event RepoRoller currently sends and
+	// forwards unrecognised ones to a log line via OnUnknown. Pass a custom
+	// Dispatcher to add handlers or replace OnUnknown (e.g. to forward to a
+	// dead-letter queue).
+	Dispatcher *Dispatcher
+
+	// Alerts, when set, routes every successfully dispatched delivery through
+	// a declarative set of Alert rules and fans matches out to their
+	// configured providers. Left nil, no alerting happens.
+	Alerts *AlertRouter
+
+	// Outbox, when set, makes alert delivery at-least-once: each event is
+	// durably recorded here before the handler acknowledges with 204, and a
+	// background worker drains it into Alerts with retries, so a delivery
+	// already accepted survives a process restart. Requires Alerts to also be
+	// set. Left nil, Alerts (if any) is invoked synchronously instead.
+	Outbox IdempotencyStore
+
+	// Metrics collects the receiver's /metrics counters. Defaults to a fresh
+	// NewMetrics().
+	Metrics *Metrics
+
+	// Now, when set, is used instead of time.Now for timestamp validation.
+	// Intended for tests; production callers should leave this nil.
+	Now func() time.Time
+}
+
+// Receiver verifies and dispatches RepoRoller webhook deliveries. It holds the
+// shared secret and replay-protection state that used to live in package-level
+// globals, so multiple receivers (e.g. one per secret during rotation) can
+// coexist in the same process.
+type Receiver struct {
+	verifier   *SignatureVerifier
+	tolerance  time.Duration
+	now        func() time.Time
+	deliveries *deliveryCache
+	handshake  HandshakeHandler
+	dispatcher *Dispatcher
+	alerts     *AlertRouter
+	outbox     IdempotencyStore
+	metrics    *Metrics
+}
+
+// NewReceiver builds a Receiver from cfg, applying defaults for any zero-value
+// fields. It panics if cfg.Secret is empty, since an unkeyed receiver would
+// silently accept unsigned requests.
+func NewReceiver(cfg ReceiverConfig) *Receiver {
+	if len(cfg.Secret) == 0 {
+		panic("webhook: ReceiverConfig.Secret must not be empty")
+	}
+
+	tolerance := cfg.TimestampTolerance
+	if tolerance <= 0 {
+		tolerance = defaultTimestampTolerance
+	}
+
+	size := cfg.DeliveryCacheSize
+	if size <= 0 {
+		size = defaultDeliveryCacheSize
+	}
+
+	ttl := cfg.DeliveryCacheTTL
+	if ttl <= 0 {
+		ttl = defaultDeliveryCacheTTL
+	}
+
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	minimum := cfg.MinimumAlgorithm
+	if minimum == AlgorithmUnspecified {
+		minimum = defaultMinimumAlgorithm
+	}
+
+	handshake := cfg.Handshake
+	if handshake == nil {
+		handshake = echoHandshakeHandler{}
+	}
+
+	dispatcher := cfg.Dispatcher
+	if dispatcher == nil {
+		dispatcher = newDispatcher()
+	}
+
+	metrics := cfg.Metrics
+	if metrics == nil {
+		metrics = NewMetrics()
+	}
+
+	if cfg.Outbox != nil && cfg.Alerts != nil {
+		worker := NewOutboxWorker(cfg.Outbox, cfg.Alerts)
+		go worker.Run(context.Background())
+	}
+
+	return &Receiver{
+		verifier:   newSignatureVerifier(cfg.Secret, minimum),
+		tolerance:  tolerance,
+		now:        now,
+		deliveries: newDeliveryCache(size, ttl),
+		handshake:  handshake,
+		dispatcher: dispatcher,
+		alerts:     cfg.Alerts,
+		outbox:     cfg.Outbox,
+		metrics:    metrics,
+	}
+}
+
+// Metrics returns the Metrics backing rc's /metrics endpoint, for mounting on
+// a handler mux.
+func (rc *Receiver) Metrics() *Metrics {
+	return rc.metrics
+}
+
+func port() string {
+	p := os.Getenv("PORT")
+	if p == "" {
+		return "8080"
+	}
+	if _, err := strconv.Atoi(p); err != nil {
+		slog.Error("PORT must be a number", "value", p)
+		os.Exit(1)
+	}
+	return p
+}
+
+// ---------------------------------------------------------------------------
+// Delivery deduplication
+// ---------------------------------------------------------------------------
+
+// deliveryCache is a size- and TTL-bounded LRU of delivery IDs, used to detect
+// retried or replayed deliveries. Evicts the least-recently-used entry once
+// the size cap is reached, and also drops entries once they age past the TTL.
+// Both seen and insert count as a use: a delivery ID that keeps getting
+// retried is kept alive at the front of the eviction queue for as long as
+// retries keep arriving, rather than aging out on a fixed insertion order.
+type deliveryCache struct {
+	mu     sync.Mutex
+	size   int
+	ttl    time.Duration
+	order  *list.List // least-recently-used at the front
+	elems  map[string]*list.Element
+	seenAt map[string]time.Time
+}
+
+func newDeliveryCache(size int, ttl time.Duration) *deliveryCache {
+	return &deliveryCache{
+		size:   size,
+		ttl:    ttl,
+		order:  list.New(),
+		elems:  make(map[string]*list.Element, size),
+		seenAt: make(map[string]time.Time, size),
+	}
+}
+
+// seen reports whether id has already been recorded (and is still within its
+// TTL) without inserting it. insert must be called separately once the
+// delivery is accepted, so that deliveries rejected for other reasons (e.g. a
+// bad signature) don't get deduplicated against.
+func (c *deliveryCache) seen(id string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	at, ok := c.seenAt[id]
+	if !ok {
+		return false
+	}
+	if now.Sub(at) > c.ttl {
+		c.evict(id)
+		return false
+	}
+	c.order.MoveToBack(c.elems[id])
+	return true
+}
+
+// insert records id as seen at now, evicting the least-recently-used entry if
+// the cache is full.
+func (c *deliveryCache) insert(id string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.seenAt[id]; ok {
+		c.order.MoveToBack(c.elems[id])
+		c.seenAt[id] = now
+		return
+	}
+
+	if c.order.Len() >= c.size {
+		if oldest := c.order.Front(); oldest != nil {
+			c.evict(oldest.Value.(string))
+		}
+	}
+
+	c.elems[id] = c.order.PushBack(id)
+	c.seenAt[id] = now
+}
+
+// evict drops id from the cache. Callers must hold c.mu.
+func (c *deliveryCache) evict(id string) {
+	if elem, ok := c.elems[id]; ok {
+		c.order.Remove(elem)
+		delete(c.elems, id)
+	}
+	delete(c.seenAt, id)
+}
+
+// ---------------------------------------------------------------------------
+// Timestamp verification
+// ---------------------------------------------------------------------------
+
+// verifyTimestamp parses the X-RepoRoller-Timestamp header (unix seconds) and
+// reports whether it falls within the receiver's configured tolerance of wall
+// clock. Rejecting stale or future timestamps bounds how long a captured
+// signature stays valid for replay.
+func (rc *Receiver) verifyTimestamp(timestampHeader string) (time.Time, bool) {
+	seconds, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	sent := time.Unix(seconds, 0)
+	drift := rc.now().Sub(sent)
+	if drift < 0 {
+		drift = -drift
+	}
+	return sent, drift <= rc.tolerance
+}
+
+// ---------------------------------------------------------------------------
+// HTTP handler
+// ---------------------------------------------------------------------------
+
+func (rc *Receiver) webhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rc.metrics.IncReceived()
+
+	// Read the full body BEFORE parsing — signature covers the raw bytes.
+	rawBody, err := io.ReadAll(io.LimitReader(r.Body, 1<<20)) // 1 MiB limit
+	if err != nil {
+		slog.Error("Failed to read request body", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	// 1. Verify the timestamp is recent enough to rule out a captured replay.
+	timestampHeader := r.Header.Get("X-RepoRoller-Timestamp")
+	sentAt, ok := rc.verifyTimestamp(timestampHeader)
+	if !ok {
+		slog.Warn("Rejected request with missing or stale timestamp",
+			"remote_addr", r.RemoteAddr, "timestamp", timestampHeader)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// 2. Verify signature, computed over "timestamp.body" rather than the body
+	// alone, so a signature cannot be replayed against a different timestamp.
+	// Whichever of the sha1/sha256/sha512 headers is strongest and meets the
+	// receiver's configured minimum is the one actually checked.
+	signedPayload := append([]byte(timestampHeader+"."), rawBody...)
+	algo, ok := rc.verifier.Verify(signedPayload, r)
+	if !ok {
+		slog.Warn("Rejected request with invalid or untrusted signature", "remote_addr", r.RemoteAddr)
+		rc.metrics.IncSignatureInvalid()
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	slog.Debug("Verified delivery signature", "algorithm", algo.String())
+
+	// 3. Reject duplicate or replayed deliveries.
+	deliveryID := r.Header.Get("X-RepoRoller-Delivery-ID")
+	if deliveryID == "" {
+		slog.Warn("Rejected request with missing delivery ID", "remote_addr", r.RemoteAddr)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if rc.deliveries.seen(deliveryID, sentAt) {
+		slog.Info("Ignoring duplicate delivery", "delivery_id", deliveryID)
+		rc.metrics.IncDuplicate()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// 4. Determine event type (and the other fields alert rules match on).
+	var env alertEnvelope
+	if err := json.Unmarshal(rawBody, &env); err != nil {
+		slog.Error("Failed to parse JSON body", "error", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	// 5. Subscription-verification handshakes are the one case that must
+	// respond with a body instead of a bare 204 — RepoRoller won't activate
+	// the endpoint until it sees its challenge echoed back.
+	if env.EventType == handshakeEventType {
+		var handshake HandshakePayload
+		if err := json.Unmarshal(rawBody, &handshake); err != nil {
+			slog.Error("Failed to deserialise handshake payload", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		response, err := rc.handshake.HandleHandshake(r.Context(), handshake.Challenge)
+		if err != nil {
+			slog.Error("Handshake handler failed", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		rc.deliveries.insert(deliveryID, sentAt)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, response)
+		return
+	}
+
+	// 6. Dispatch on event type.
+	if err := rc.dispatcher.Dispatch(r.Context(), env.EventType, rawBody); err != nil {
+		slog.Error("Event handler failed", "event_type", env.EventType, "error", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	// 7. Route the delivery through any matching alert rules. If an outbox is
+	// configured, the event is durably enqueued and a background worker takes
+	// it from here with retries — that single write is what makes this
+	// at-least-once rather than fire-and-forget. Without an outbox, alerts
+	// are sent synchronously, as before.
+	event := eventFromEnvelope(env, rawBody)
+	if rc.outbox != nil {
+		accepted, err := rc.outbox.Enqueue(r.Context(), event.EventID, event)
+		if err != nil {
+			slog.Error("Failed to enqueue event to outbox", "event_id", event.EventID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if !accepted {
+			slog.Info("Ignoring duplicate event", "event_id", event.EventID)
+			rc.metrics.IncDuplicate()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	} else if rc.alerts != nil {
+		rc.alerts.Route(r.Context(), event)
+	}
+
+	// Only remember the delivery once it has been accepted and processed, so a
+	// delivery rejected for a bad signature can still be retried legitimately.
+	rc.deliveries.insert(deliveryID, sentAt)
+
+	// Always acknowledge promptly — processing is fire-and-forget from sender's perspective.
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ---------------------------------------------------------------------------
+// Entry point
+// ---------------------------------------------------------------------------
+
+func main() {
+	secret := os.Getenv("WEBHOOK_SECRET")
+	if secret == "" {
+		slog.Error("WEBHOOK_SECRET environment variable is not set")
+		os.Exit(1)
+	}
+
+	receiver := NewReceiver(ReceiverConfig{Secret: []byte(secret)})
+
+	addr := fmt.Sprintf("0.0.0.0:%s", port())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook", receiver.webhookHandler)
+	mux.Handle("/metrics", receiver.Metrics())
+
+	slog.Info("RepoRoller webhook receiver listening", "addr", addr)
+	// In production, terminate TLS at a reverse proxy / load balancer.
+	// Your notifications.toml endpoint URL must use https://.
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		slog.Error("Server failed", "error", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeliveryCache_SeenWithinTTL(t *testing.T) {
+	c := newDeliveryCache(10, time.Minute)
+	now := time.Now()
+
+	c.insert("a", now)
+	if !c.seen("a", now.Add(30*time.Second)) {
+		t.Fatal("expected id to be seen within its TTL")
+	}
+}
+
+func TestDeliveryCache_NotSeenAfterTTL(t *testing.T) {
+	c := newDeliveryCache(10, time.Minute)
+	now := time.Now()
+
+	c.insert("a", now)
+	if c.seen("a", now.Add(2*time.Minute)) {
+		t.Fatal("expected id to no longer be seen once its TTL has elapsed")
+	}
+}
+
+func TestDeliveryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newDeliveryCache(2, time.Hour)
+	now := time.Now()
+
+	c.insert("a", now)
+	c.insert("b", now)
+	// Accessing "a" again should make "b" the least-recently-used entry.
+	c.seen("a", now)
+	c.insert("c", now)
+
+	if c.seen("b", now) {
+		t.Fatal("expected b to have been evicted as the least-recently-used entry")
+	}
+	if !c.seen("a", now) {
+		t.Fatal("expected a to still be present, since it was used more recently than b")
+	}
+	if !c.seen("c", now) {
+		t.Fatal("expected c to be present")
+	}
+}
+
+func TestDeliveryCache_InsertOnExistingIDPromotesIt(t *testing.T) {
+	c := newDeliveryCache(2, time.Hour)
+	now := time.Now()
+
+	c.insert("a", now)
+	c.insert("b", now)
+	c.insert("a", now) // re-insert, e.g. a retried delivery's insert call.
+	c.insert("c", now)
+
+	if c.seen("b", now) {
+		t.Fatal("expected b to have been evicted")
+	}
+	if !c.seen("a", now) {
+		t.Fatal("expected a to still be present after being re-inserted")
+	}
+}
+
+func TestVerifyTimestamp_WithinTolerance(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	rc := NewReceiver(ReceiverConfig{
+		Secret:             []byte("secret"),
+		TimestampTolerance: 5 * time.Minute,
+		Now:                func() time.Time { return now },
+	})
+
+	_, ok := rc.verifyTimestamp("1700000000")
+	if !ok {
+		t.Fatal("expected an exactly-matching timestamp to be within tolerance")
+	}
+
+	_, ok = rc.verifyTimestamp("1699999700") // 300s in the past
+	if !ok {
+		t.Fatal("expected a timestamp within tolerance to be accepted")
+	}
+}
+
+func TestVerifyTimestamp_OutsideTolerance(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	rc := NewReceiver(ReceiverConfig{
+		Secret:             []byte("secret"),
+		TimestampTolerance: 5 * time.Minute,
+		Now:                func() time.Time { return now },
+	})
+
+	if _, ok := rc.verifyTimestamp("1699999000"); ok { // ~1000s stale
+		t.Fatal("expected a stale timestamp to be rejected")
+	}
+	if _, ok := rc.verifyTimestamp("1700001000"); ok { // ~1000s in the future
+		t.Fatal("expected a future timestamp to be rejected")
+	}
+}
+
+func TestVerifyTimestamp_RejectsMalformedHeader(t *testing.T) {
+	rc := NewReceiver(ReceiverConfig{Secret: []byte("secret")})
+	if _, ok := rc.verifyTimestamp("not-a-number"); ok {
+		t.Fatal("expected a non-numeric timestamp header to be rejected")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// Algorithm identifies an HMAC hash algorithm a delivery may be signed with.
+// Values are ordered from weakest to strongest so they can be compared
+// directly (AlgorithmSHA256 > AlgorithmSHA1). The zero value,
+// AlgorithmUnspecified, is not a usable algorithm — it lets ReceiverConfig
+// distinguish "no minimum configured" from "minimum explicitly set to sha1".
+type Algorithm int
+
+const (
+	AlgorithmUnspecified Algorithm = iota
+	AlgorithmSHA1
+	AlgorithmSHA256
+	AlgorithmSHA512
+)
+
+// String returns the lowercase algorithm name used in RepoRoller's config and logs.
+func (a Algorithm) String() string {
+	switch a {
+	case AlgorithmSHA1:
+		return "sha1"
+	case AlgorithmSHA256:
+		return "sha256"
+	case AlgorithmSHA512:
+		return "sha512"
+	default:
+		return "unknown"
+	}
+}
+
+// signatureSpec describes how to read and verify the header for one algorithm.
+type signatureSpec struct {
+	header  string
+	prefix  string
+	newHash func() hash.Hash
+}
+
+// signatureSpecs mirrors how GitHub-style receivers accept X-Hub-Signature and
+// X-Hub-Signature-256 simultaneously: every header RepoRoller may send is
+// checked, not just the one the receiver happens to look for first.
+var signatureSpecs = map[Algorithm]signatureSpec{
+	AlgorithmSHA1:   {header: "X-RepoRoller-Signature", prefix: "sha1=", newHash: sha1.New},
+	AlgorithmSHA256: {header: "X-RepoRoller-Signature-256", prefix: "sha256=", newHash: sha256.New},
+	AlgorithmSHA512: {header: "X-RepoRoller-Signature-512", prefix: "sha512=", newHash: sha512.New},
+}
+
+// strongestFirst lists algorithms strongest-first, for picking which header to
+// trust when a delivery carries more than one.
+var strongestFirst = []Algorithm{AlgorithmSHA512, AlgorithmSHA256, AlgorithmSHA1}
+
+// SignatureVerifier checks a delivery's HMAC signature against whichever of
+// X-RepoRoller-Signature, -256 and -512 are present, trusting only algorithms
+// at or above a configured minimum.
+type SignatureVerifier struct {
+	secret  []byte
+	minimum Algorithm
+}
+
+// newSignatureVerifier builds a SignatureVerifier that rejects any algorithm
+// weaker than minimum, regardless of whether its header is present.
+func newSignatureVerifier(secret []byte, minimum Algorithm) *SignatureVerifier {
+	return &SignatureVerifier{secret: secret, minimum: minimum}
+}
+
+// Verify picks the strongest signature header present on r that meets the
+// verifier's minimum trusted algorithm, and checks it against signedPayload.
+// It reports which algorithm was used and whether the request is authentic.
+// A request is rejected outright if no header meets the minimum — weaker
+// headers are never consulted as a fallback once a trusted one is found.
+func (v *SignatureVerifier) Verify(signedPayload []byte, r *http.Request) (Algorithm, bool) {
+	for _, algo := range strongestFirst {
+		if algo < v.minimum {
+			continue
+		}
+		spec := signatureSpecs[algo]
+		header := r.Header.Get(spec.header)
+		if header == "" {
+			continue
+		}
+		return algo, v.verifyOne(spec, signedPayload, header)
+	}
+	return 0, false
+}
+
+func (v *SignatureVerifier) verifyOne(spec signatureSpec, signedPayload []byte, signatureHeader string) bool {
+	if !strings.HasPrefix(signatureHeader, spec.prefix) {
+		return false
+	}
+
+	receivedHex := signatureHeader[len(spec.prefix):]
+	receivedBytes, err := hex.DecodeString(receivedHex)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(spec.newHash, v.secret)
+	mac.Write(signedPayload)
+	computed := mac.Sum(nil)
+
+	// Constant-time comparison — never use bytes.Equal here.
+	return hmac.Equal(computed, receivedBytes)
+}
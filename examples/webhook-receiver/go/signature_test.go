@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func signHeader(secret, payload []byte, algo Algorithm) string {
+	var mac interface {
+		Write([]byte) (int, error)
+		Sum([]byte) []byte
+	}
+	switch algo {
+	case AlgorithmSHA1:
+		mac = hmac.New(sha1.New, secret)
+	case AlgorithmSHA256:
+		mac = hmac.New(sha256.New, secret)
+	case AlgorithmSHA512:
+		mac = hmac.New(sha512.New, secret)
+	}
+	mac.Write(payload)
+	return signatureSpecs[algo].prefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSignatureVerifier_PicksStrongestPresentHeader(t *testing.T) {
+	secret := []byte("sekret")
+	payload := []byte("1690000000.{\"event_type\":\"repository.created\"}")
+
+	r := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	r.Header.Set(signatureSpecs[AlgorithmSHA1].header, signHeader(secret, payload, AlgorithmSHA1))
+	r.Header.Set(signatureSpecs[AlgorithmSHA256].header, signHeader(secret, payload, AlgorithmSHA256))
+
+	v := newSignatureVerifier(secret, AlgorithmUnspecified)
+	algo, ok := v.Verify(payload, r)
+	if !ok {
+		t.Fatal("expected verification to succeed")
+	}
+	if algo != AlgorithmSHA256 {
+		t.Fatalf("expected sha256 to be preferred over sha1, got %s", algo)
+	}
+}
+
+func TestSignatureVerifier_RejectsBelowMinimum(t *testing.T) {
+	secret := []byte("sekret")
+	payload := []byte("payload")
+
+	r := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	r.Header.Set(signatureSpecs[AlgorithmSHA1].header, signHeader(secret, payload, AlgorithmSHA1))
+
+	v := newSignatureVerifier(secret, AlgorithmSHA256)
+	if _, ok := v.Verify(payload, r); ok {
+		t.Fatal("expected a sha1-only delivery to be rejected when the minimum is sha256")
+	}
+}
+
+func TestSignatureVerifier_DoesNotFallBackToWeakerHeader(t *testing.T) {
+	secret := []byte("sekret")
+	payload := []byte("payload")
+
+	r := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	// A valid sha1 header, but a corrupted sha256 header — the verifier must
+	// not silently fall back to the valid-but-weaker sha1 header.
+	r.Header.Set(signatureSpecs[AlgorithmSHA1].header, signHeader(secret, payload, AlgorithmSHA1))
+	r.Header.Set(signatureSpecs[AlgorithmSHA256].header, "sha256=not-a-real-signature")
+
+	v := newSignatureVerifier(secret, AlgorithmUnspecified)
+	algo, ok := v.Verify(payload, r)
+	if ok {
+		t.Fatal("expected verification to fail against a corrupted sha256 header")
+	}
+	if algo != AlgorithmSHA256 {
+		t.Fatalf("expected the sha256 header to be the one attempted, got %s", algo)
+	}
+}
+
+func TestSignatureVerifier_RejectsWrongSecret(t *testing.T) {
+	payload := []byte("payload")
+	r := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	r.Header.Set(signatureSpecs[AlgorithmSHA256].header, signHeader([]byte("right-secret"), payload, AlgorithmSHA256))
+
+	v := newSignatureVerifier([]byte("wrong-secret"), AlgorithmUnspecified)
+	if _, ok := v.Verify(payload, r); ok {
+		t.Fatal("expected verification to fail with the wrong secret")
+	}
+}
+
+func TestSignatureVerifier_RejectsMissingHeaders(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	v := newSignatureVerifier([]byte("secret"), AlgorithmUnspecified)
+	if _, ok := v.Verify([]byte("payload"), r); ok {
+		t.Fatal("expected verification to fail when no signature header is present")
+	}
+}
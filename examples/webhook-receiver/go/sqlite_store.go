@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteIdempotencyStore is an IdempotencyStore backed by a local SQLite
+// database, the recommended choice for a single-instance receiver that needs
+// its outbox to survive a restart without standing up a separate service.
+type SQLiteIdempotencyStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteIdempotencyStore opens (creating if necessary) the outbox table in
+// the SQLite database at dataSourceName, e.g. "outbox.db".
+func NewSQLiteIdempotencyStore(dataSourceName string) (*SQLiteIdempotencyStore, error) {
+	db, err := sql.Open("sqlite", dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: open sqlite outbox: %w", err)
+	}
+
+	const schema = `
+CREATE TABLE IF NOT EXISTS outbox (
+	event_id     TEXT PRIMARY KEY,
+	payload      BLOB NOT NULL,
+	attempts     INTEGER NOT NULL DEFAULT 0,
+	next_attempt INTEGER NOT NULL DEFAULT 0
+)`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("webhook: create outbox table: %w", err)
+	}
+
+	return &SQLiteIdempotencyStore{db: db}, nil
+}
+
+// Close releases the underlying database handle.
+func (s *SQLiteIdempotencyStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteIdempotencyStore) Enqueue(ctx context.Context, eventID string, event Event) (bool, error) {
+	payload, err := marshalEvent(event)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("webhook: begin outbox transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM outbox WHERE event_id = ?`, eventID).Scan(&exists)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// Not seen before — fall through and insert it.
+	case err != nil:
+		return false, fmt.Errorf("webhook: check outbox entry: %w", err)
+	default:
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO outbox (event_id, payload, attempts, next_attempt) VALUES (?, ?, 0, 0)`,
+		eventID, payload,
+	); err != nil {
+		return false, fmt.Errorf("webhook: insert outbox entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("webhook: commit outbox transaction: %w", err)
+	}
+	return true, nil
+}
+
+func (s *SQLiteIdempotencyStore) Claim(ctx context.Context, max int) ([]OutboxEntry, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT event_id, payload, attempts, next_attempt FROM outbox WHERE next_attempt <= ? LIMIT ?`,
+		time.Now().Unix(), max,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: claim outbox entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []OutboxEntry
+	for rows.Next() {
+		var (
+			eventID         string
+			payload         []byte
+			attempts        int
+			nextAttemptUnix int64
+		)
+		if err := rows.Scan(&eventID, &payload, &attempts, &nextAttemptUnix); err != nil {
+			return nil, fmt.Errorf("webhook: scan outbox entry: %w", err)
+		}
+
+		event, err := unmarshalEvent(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, OutboxEntry{
+			EventID:     eventID,
+			Event:       event,
+			Attempts:    attempts,
+			NextAttempt: time.Unix(nextAttemptUnix, 0),
+		})
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQLiteIdempotencyStore) Complete(ctx context.Context, eventID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM outbox WHERE event_id = ?`, eventID); err != nil {
+		return fmt.Errorf("webhook: complete outbox entry: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteIdempotencyStore) Retry(ctx context.Context, eventID string, nextAttempt time.Time) error {
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE outbox SET attempts = attempts + 1, next_attempt = ? WHERE event_id = ?`,
+		nextAttempt.Unix(), eventID,
+	); err != nil {
+		return fmt.Errorf("webhook: reschedule outbox entry: %w", err)
+	}
+	return nil
+}